@@ -0,0 +1,78 @@
+package tcp
+
+import (
+	"io"
+	"testing"
+
+	"github.com/unistack-org/micro/v3/codec"
+	"github.com/unistack-org/micro/v3/server"
+)
+
+// tagCodec is a no-op codec.Codec whose identity (via name) lets a test tell
+// which map entry newCodec resolved to.
+type tagCodec struct{ name string }
+
+func (c *tagCodec) ReadHeader(io.Reader, *codec.Message, codec.MessageType) error { return nil }
+func (c *tagCodec) ReadBody(io.Reader, interface{}) error                         { return nil }
+func (c *tagCodec) Write(io.Writer, *codec.Message, interface{}) error            { return nil }
+func (c *tagCodec) String() string                                                { return c.name }
+
+func TestNewCodecStripsParamsAndFallsBackToDefault(t *testing.T) {
+	jsonCodec := &tagCodec{name: "json"}
+	defaultCodec := &tagCodec{name: "default"}
+
+	prev := server.DefaultCodecs[defaultContentTypeDefault]
+	server.DefaultCodecs[defaultContentTypeDefault] = defaultCodec
+	defer func() {
+		if prev == nil {
+			delete(server.DefaultCodecs, defaultContentTypeDefault)
+		} else {
+			server.DefaultCodecs[defaultContentTypeDefault] = prev
+		}
+	}()
+
+	srv := NewServer().(*tcpServer)
+	srv.opts.Codecs = map[string]codec.Codec{
+		"application/json": jsonCodec,
+	}
+
+	cf, err := srv.newCodec("application/json; charset=utf-8")
+	if err != nil {
+		t.Fatalf("newCodec: %v", err)
+	}
+	if cf != codec.Codec(jsonCodec) {
+		t.Fatalf("expected the json codec for a parameterized Content-Type, got %v", cf)
+	}
+
+	cf, err = srv.newCodec("application/x-unregistered")
+	if err != nil {
+		t.Fatalf("newCodec fallback: %v", err)
+	}
+	if cf != codec.Codec(defaultCodec) {
+		t.Fatalf("expected fallback to the default codec, got %v", cf)
+	}
+}
+
+func TestNewCodecHonorsConfiguredDefaultContentType(t *testing.T) {
+	customDefault := &tagCodec{name: "custom-default"}
+
+	prev := server.DefaultCodecs["application/custom"]
+	server.DefaultCodecs["application/custom"] = customDefault
+	defer func() {
+		if prev == nil {
+			delete(server.DefaultCodecs, "application/custom")
+		} else {
+			server.DefaultCodecs["application/custom"] = prev
+		}
+	}()
+
+	srv := NewServer(DefaultContentType("application/custom")).(*tcpServer)
+
+	cf, err := srv.newCodec("application/unknown")
+	if err != nil {
+		t.Fatalf("newCodec: %v", err)
+	}
+	if cf != codec.Codec(customDefault) {
+		t.Fatalf("expected the configured DefaultContentType codec, got %v", cf)
+	}
+}
@@ -0,0 +1,114 @@
+package tcp
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/unistack-org/micro/v3/registry"
+	"github.com/unistack-org/micro/v3/server"
+)
+
+func TestWaitGracefulWaitsForInFlightWork(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	done := make(chan struct{})
+	go func() {
+		waitGraceful(&wg, 0, nil)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("waitGraceful returned before in-flight work finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	wg.Done()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("waitGraceful did not return after in-flight work finished")
+	}
+}
+
+func TestWaitGracefulTimesOut(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(1) // deliberately never Done, to force the timeout branch
+
+	var timedOut bool
+	waitGraceful(&wg, 10*time.Millisecond, func() { timedOut = true })
+
+	if !timedOut {
+		t.Fatal("expected onTimeout to be called once the timeout elapsed")
+	}
+}
+
+// blockingHandler signals started once a connection is being served, then
+// blocks until release is closed, simulating an in-flight request.
+type blockingHandler struct {
+	server.Handler
+	started chan struct{}
+	release chan struct{}
+}
+
+func (h *blockingHandler) Handler() interface{}            { return h }
+func (h *blockingHandler) Endpoints() []*registry.Endpoint { return nil }
+
+func (h *blockingHandler) Serve(conn net.Conn) {
+	defer conn.Close()
+	close(h.started)
+	<-h.release
+}
+
+func TestStopWaitsForInFlightConnectionAfterInitWait(t *testing.T) {
+	srv := NewServer(server.Address("127.0.0.1:0"))
+
+	var wg sync.WaitGroup
+	if err := srv.Init(Wait(&wg)); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	h := &blockingHandler{started: make(chan struct{}), release: make(chan struct{})}
+	if err := srv.Handle(h); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	conn, err := net.Dial("tcp", srv.Options().Address)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	select {
+	case <-h.started:
+	case <-time.After(time.Second):
+		t.Fatal("handler never started serving the connection")
+	}
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- srv.Stop() }()
+
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned before the in-flight connection finished; Wait set via Init was not applied")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(h.release)
+
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatalf("Stop: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Stop did not return after the in-flight connection finished")
+	}
+}
@@ -0,0 +1,214 @@
+package tcp
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/unistack-org/micro/v3/broker"
+	"github.com/unistack-org/micro/v3/server"
+)
+
+type waitGroupKey struct{}
+
+type gracefulTimeoutKey struct{}
+
+type recoverPanicKey struct{}
+
+type errorHandlerKey struct{}
+
+type subErrorHandlerKey struct{}
+
+type defaultContentTypeKey struct{}
+
+const defaultContentTypeDefault = "application/octet-stream"
+
+// DefaultContentType sets the codec looked up by newCodec when the request's
+// Content-Type (after stripping any "; charset=..." parameter) has no exact
+// match in opts.Codecs. Defaults to "application/octet-stream".
+func DefaultContentType(ct string) server.Option {
+	return func(o *server.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, defaultContentTypeKey{}, ct)
+	}
+}
+
+func defaultContentType(ctx context.Context) string {
+	if ctx == nil {
+		return defaultContentTypeDefault
+	}
+	ct, ok := ctx.Value(defaultContentTypeKey{}).(string)
+	if !ok || ct == "" {
+		return defaultContentTypeDefault
+	}
+	return ct
+}
+
+// ErrorHandler is invoked by serve when a connection handler panics and the
+// panic is recovered (see RecoverPanic), with the connection and an error
+// describing the panic. Errors that occur inside Handler.Serve without a
+// panic are not reported to it.
+type ErrorHandler func(ctx context.Context, h server.Handler, conn net.Conn, err error)
+
+// SubscriberErrorHandler is invoked instead of bare error propagation when
+// the codec lookup, a header or body decode, or a subscriber invocation
+// fails while handling a broker event. Its
+// return value becomes createSubHandler's return value, so it controls
+// AutoAck: return the original err (or any non-nil error) to leave the event
+// un-acked, or nil to explicitly ack it after e.g. NACKing the event itself.
+type SubscriberErrorHandler func(ctx context.Context, sb server.Subscriber, evt broker.Event, err error) error
+
+// WithErrorHandler sets the hook invoked when a recovered panic ends the
+// serving of a connection, letting callers log structured diagnostics or
+// write a framed error reply back on the socket. Without it the panic is
+// only logged.
+func WithErrorHandler(eh ErrorHandler) server.Option {
+	return func(o *server.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, errorHandlerKey{}, eh)
+	}
+}
+
+// WithSubscriberErrorHandler sets the hook invoked on subscriber-path
+// errors, letting callers log structured diagnostics or NACK the broker
+// event instead of the default behavior of returning a bare error.
+func WithSubscriberErrorHandler(seh SubscriberErrorHandler) server.Option {
+	return func(o *server.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, subErrorHandlerKey{}, seh)
+	}
+}
+
+func errorHandler(ctx context.Context) ErrorHandler {
+	if ctx == nil {
+		return nil
+	}
+	eh, ok := ctx.Value(errorHandlerKey{}).(ErrorHandler)
+	if !ok {
+		return nil
+	}
+	return eh
+}
+
+func subErrorHandler(ctx context.Context) SubscriberErrorHandler {
+	if ctx == nil {
+		return nil
+	}
+	seh, ok := ctx.Value(subErrorHandlerKey{}).(SubscriberErrorHandler)
+	if !ok {
+		return nil
+	}
+	return seh
+}
+
+// Wait toggles in-flight connection tracking on the server. When set, serve
+// adds to the wait group before spawning each connection handler, and Stop,
+// after closing the listener and deregistering, blocks on it (up to
+// GracefulTimeout). Connections still open when the timeout elapses are not
+// closed by Stop.
+// Passing a non-nil wg lets the caller share it with other components
+// instead of the server owning a private one.
+func Wait(wg *sync.WaitGroup) server.Option {
+	return func(o *server.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		if wg == nil {
+			wg = new(sync.WaitGroup)
+		}
+		o.Context = context.WithValue(o.Context, waitGroupKey{}, wg)
+	}
+}
+
+// GracefulTimeout bounds how long Stop waits on the Wait group for in-flight
+// connections to finish; the listener is already closed by then. When it
+// elapses Stop logs the timeout and returns, leaving those connections open.
+// Zero (the default) means wait indefinitely.
+func GracefulTimeout(td time.Duration) server.Option {
+	return func(o *server.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, gracefulTimeoutKey{}, td)
+	}
+}
+
+// RecoverPanic toggles the deferred recover wrapped around connection
+// handlers and subscriber invocations. It defaults to true; pass false to
+// let panics propagate, e.g. in tests that want to observe them directly.
+func RecoverPanic(b bool) server.Option {
+	return func(o *server.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, recoverPanicKey{}, b)
+	}
+}
+
+func recoverPanic(ctx context.Context) bool {
+	if ctx == nil {
+		return true
+	}
+	b, ok := ctx.Value(recoverPanicKey{}).(bool)
+	if !ok {
+		return true
+	}
+	return b
+}
+
+func waitGroup(ctx context.Context) *sync.WaitGroup {
+	if ctx == nil {
+		return nil
+	}
+	wg, ok := ctx.Value(waitGroupKey{}).(*sync.WaitGroup)
+	if !ok {
+		return nil
+	}
+	return wg
+}
+
+func gracefulTimeout(ctx context.Context) time.Duration {
+	if ctx == nil {
+		return 0
+	}
+	td, ok := ctx.Value(gracefulTimeoutKey{}).(time.Duration)
+	if !ok {
+		return 0
+	}
+	return td
+}
+
+// waitGraceful blocks on wg, if non-nil, until it completes or timeout
+// elapses, calling onTimeout in the latter case. A non-positive timeout
+// waits indefinitely. A nil wg returns immediately.
+func waitGraceful(wg *sync.WaitGroup, timeout time.Duration, onTimeout func()) {
+	if wg == nil {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	if timeout <= 0 {
+		<-done
+		return
+	}
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		if onTimeout != nil {
+			onTimeout()
+		}
+	}
+}
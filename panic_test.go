@@ -0,0 +1,66 @@
+package tcp
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/unistack-org/micro/v3/registry"
+	"github.com/unistack-org/micro/v3/server"
+)
+
+// panicOnceHandler panics on its first invocation and succeeds afterwards,
+// letting the test prove a panic in one connection doesn't take down the
+// accept loop (or the test process itself, since an unrecovered panic in a
+// goroutine crashes the whole program).
+type panicOnceHandler struct {
+	server.Handler
+	calls int32
+}
+
+func (h *panicOnceHandler) Handler() interface{}            { return h }
+func (h *panicOnceHandler) Endpoints() []*registry.Endpoint { return nil }
+
+func (h *panicOnceHandler) Serve(conn net.Conn) {
+	defer conn.Close()
+	if atomic.AddInt32(&h.calls, 1) == 1 {
+		panic("boom")
+	}
+}
+
+func TestServeRecoversHandlerPanicAndKeepsAccepting(t *testing.T) {
+	srv := NewServer(server.Address("127.0.0.1:0"))
+
+	h := &panicOnceHandler{}
+	if err := srv.Handle(h); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer srv.Stop()
+
+	addr := srv.Options().Address
+
+	conn1, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial 1: %v", err)
+	}
+	conn1.Close()
+
+	// give the panicking goroutine time to run and recover
+	time.Sleep(50 * time.Millisecond)
+
+	conn2, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial 2: %v", err)
+	}
+	conn2.Close()
+
+	time.Sleep(50 * time.Millisecond)
+
+	if got := atomic.LoadInt32(&h.calls); got != 2 {
+		t.Fatalf("expected the handler to be invoked twice (panic recovered), got %d", got)
+	}
+}
@@ -0,0 +1,206 @@
+package tcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/unistack-org/micro/v3/codec"
+	"github.com/unistack-org/micro/v3/metadata"
+	"github.com/unistack-org/micro/v3/server"
+)
+
+// frameType identifies the kind of framed message multiplexed onto a single
+// TCP connection by a streaming endpoint.
+type frameType byte
+
+const (
+	frameOpen frameType = iota
+	frameData
+	frameClose
+	frameError
+)
+
+// frame is the wire unit for streaming endpoints: an 8-byte stream ID, a
+// 1-byte type and a 4-byte payload length, followed by the codec-encoded
+// payload itself. Framing lets multiple concurrent streams share one socket.
+type frame struct {
+	streamID uint64
+	typ      frameType
+	payload  []byte
+}
+
+const frameHeaderSize = 8 + 1 + 4
+
+func writeFrame(w io.Writer, f *frame) error {
+	hdr := make([]byte, frameHeaderSize)
+	binary.BigEndian.PutUint64(hdr[0:8], f.streamID)
+	hdr[8] = byte(f.typ)
+	binary.BigEndian.PutUint32(hdr[9:13], uint32(len(f.payload)))
+	if _, err := w.Write(hdr); err != nil {
+		return err
+	}
+	if len(f.payload) == 0 {
+		return nil
+	}
+	_, err := w.Write(f.payload)
+	return err
+}
+
+func readFrame(r io.Reader) (*frame, error) {
+	hdr := make([]byte, frameHeaderSize)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, err
+	}
+	f := &frame{
+		streamID: binary.BigEndian.Uint64(hdr[0:8]),
+		typ:      frameType(hdr[8]),
+	}
+	if n := binary.BigEndian.Uint32(hdr[9:13]); n > 0 {
+		f.payload = make([]byte, n)
+		if _, err := io.ReadFull(r, f.payload); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// tcpStream implements server.Stream over a frame multiplexed onto a shared
+// net.Conn. conn writes are serialized through mu since several streams may
+// write concurrently on the same connection.
+type tcpStream struct {
+	id     uint64
+	ctx    context.Context
+	conn   net.Conn
+	codec  codec.Codec
+	mu     *sync.Mutex
+	in     chan *frame
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (s *tcpStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *tcpStream) Send(msg interface{}) error {
+	buf := &bytes.Buffer{}
+	if err := s.codec.Write(buf, &codec.Message{Type: codec.Event}, msg); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return writeFrame(s.conn, &frame{streamID: s.id, typ: frameData, payload: buf.Bytes()})
+}
+
+func (s *tcpStream) Recv(msg interface{}) error {
+	select {
+	case f, ok := <-s.in:
+		if !ok {
+			return io.EOF
+		}
+		switch f.typ {
+		case frameClose:
+			return io.EOF
+		case frameError:
+			return fmt.Errorf("stream %d: %s", s.id, f.payload)
+		}
+		return s.codec.ReadBody(bytes.NewBuffer(f.payload), msg)
+	case <-s.closed:
+		return io.EOF
+	}
+}
+
+func (s *tcpStream) Close() error {
+	s.once.Do(func() {
+		close(s.closed)
+		s.mu.Lock()
+		writeFrame(s.conn, &frame{streamID: s.id, typ: frameClose})
+		s.mu.Unlock()
+	})
+	return nil
+}
+
+// serveStreams reads frames off conn until it errors (including on close),
+// demultiplexing them by stream ID: a frameOpen allocates a *tcpStream and
+// hands it to open in its own goroutine, frameData/frameClose/frameError are
+// routed to the matching stream's Recv. Concurrent streams share conn safely
+// since writes are serialized per call to tcpStream.Send/Close.
+//
+// WIP: this is not yet wired into any connection-serving code in this tree.
+// tcpHandler.Serve — the per-connection dispatcher that would call this for
+// endpoints marked via newStream — lives in a file that doesn't exist in
+// this snapshot. Land this groundwork now and wire it in once that file is
+// added; don't treat streaming as functional end-to-end until then.
+func serveStreams(ctx context.Context, conn net.Conn, cf codec.Codec, open func(st *tcpStream)) error {
+	var writeMu sync.Mutex
+
+	var mu sync.Mutex
+	streams := make(map[uint64]*tcpStream)
+
+	for {
+		f, err := readFrame(conn)
+		if err != nil {
+			mu.Lock()
+			for _, st := range streams {
+				st.once.Do(func() { close(st.closed) })
+			}
+			mu.Unlock()
+			return err
+		}
+
+		switch f.typ {
+		case frameOpen:
+			st := &tcpStream{
+				id:     f.streamID,
+				ctx:    ctx,
+				conn:   conn,
+				codec:  cf,
+				mu:     &writeMu,
+				in:     make(chan *frame, 8),
+				closed: make(chan struct{}),
+			}
+			mu.Lock()
+			streams[f.streamID] = st
+			mu.Unlock()
+			go open(st)
+		case frameData, frameClose, frameError:
+			mu.Lock()
+			st, ok := streams[f.streamID]
+			if ok && f.typ != frameData {
+				delete(streams, f.streamID)
+			}
+			mu.Unlock()
+			if ok {
+				st.in <- f
+			}
+		}
+	}
+}
+
+// newStream marks the given handler method names as streaming endpoints,
+// tagging their registry.Endpoint metadata with "stream"="true". It is kept
+// unexported since tcpHandler.Serve does not yet check that metadata or call
+// serveStreams for matching methods — see serveStreams for the current
+// wiring status. Export this once that wiring lands; until then a public
+// option would let callers depend on streaming that silently doesn't work.
+func newStream(methods ...string) server.HandlerOption {
+	return func(o *server.HandlerOptions) {
+		if o.Metadata == nil {
+			o.Metadata = make(map[string]metadata.Metadata)
+		}
+		for _, m := range methods {
+			md, ok := o.Metadata[m]
+			if !ok {
+				md = metadata.New(1)
+			}
+			md.Set("stream", "true")
+			o.Metadata[m] = md
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package tcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/unistack-org/micro/v3/codec"
+)
+
+// jsonLineCodec is a minimal codec.Codec for exercising serveStreams/tcpStream
+// without depending on a concrete codec implementation.
+type jsonLineCodec struct{}
+
+func (jsonLineCodec) ReadHeader(io.Reader, *codec.Message, codec.MessageType) error { return nil }
+
+func (jsonLineCodec) ReadBody(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+func (jsonLineCodec) Write(w io.Writer, _ *codec.Message, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (jsonLineCodec) String() string { return "jsonline" }
+
+func TestServeStreamsDemuxesConcurrentStreamsOnOneConn(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		serveStreams(context.Background(), conn, jsonLineCodec{}, func(st *tcpStream) {
+			for {
+				var in string
+				if err := st.Recv(&in); err != nil {
+					return
+				}
+				if err := st.Send("echo:" + in); err != nil {
+					return
+				}
+			}
+		})
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	send := func(id uint64, typ frameType, payload interface{}) {
+		var buf bytes.Buffer
+		if payload != nil {
+			if err := json.NewEncoder(&buf).Encode(payload); err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+		}
+		if err := writeFrame(conn, &frame{streamID: id, typ: typ, payload: buf.Bytes()}); err != nil {
+			t.Fatalf("writeFrame: %v", err)
+		}
+	}
+
+	// interleave two concurrent streams on the single connection
+	send(1, frameOpen, nil)
+	send(2, frameOpen, nil)
+	send(1, frameData, "one")
+	send(2, frameData, "two")
+
+	got := make(map[uint64]string)
+	for i := 0; i < 2; i++ {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		f, err := readFrame(conn)
+		if err != nil {
+			t.Fatalf("readFrame: %v", err)
+		}
+		var out string
+		if err := json.Unmarshal(f.payload, &out); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		got[f.streamID] = out
+	}
+
+	if got[1] != "echo:one" || got[2] != "echo:two" {
+		t.Fatalf("expected each stream to get its own echo, got %#v", got)
+	}
+
+	conn.Close()
+
+	select {
+	case <-serverDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("server goroutine did not exit after the connection closed")
+	}
+}
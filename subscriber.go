@@ -4,28 +4,68 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"reflect"
+	"runtime/debug"
 	"strings"
 	"unicode"
 	"unicode/utf8"
 
 	"github.com/unistack-org/micro/v3/broker"
 	"github.com/unistack-org/micro/v3/codec"
+	"github.com/unistack-org/micro/v3/logger"
 	"github.com/unistack-org/micro/v3/metadata"
 	"github.com/unistack-org/micro/v3/registry"
 	"github.com/unistack-org/micro/v3/server"
 )
 
 const (
-	subSig = "func(context.Context, interface{}) error"
+	subSig       = "func(context.Context, interface{}) error"
+	subStreamSig = "func(context.Context, server.Stream) error"
 )
 
 var typeOfError = reflect.TypeOf((*error)(nil)).Elem()
 
+var typeOfStream = reflect.TypeOf((*server.Stream)(nil)).Elem()
+
+func isStreamType(t reflect.Type) bool {
+	return t == typeOfStream
+}
+
 type handler struct {
 	method  reflect.Value
 	reqType reflect.Type
 	ctxType reflect.Type
+	stream  bool
+}
+
+// eventStream adapts a single broker event into a server.Stream for
+// subscriber methods declared with a server.Stream argument. Recv yields the
+// event's decoded body exactly once; Send is unsupported since a broker
+// subscription is receive-only in this dispatch model.
+type eventStream struct {
+	ctx   context.Context
+	cf    codec.Codec
+	buf   *bytes.Buffer
+	recvd bool
+}
+
+func (s *eventStream) Context() context.Context { return s.ctx }
+
+func (s *eventStream) Send(interface{}) error {
+	return fmt.Errorf("subscriber stream: send not supported")
+}
+
+func (s *eventStream) Recv(msg interface{}) error {
+	if s.recvd {
+		return io.EOF
+	}
+	s.recvd = true
+	return s.cf.ReadBody(s.buf, msg)
+}
+
+func (s *eventStream) Close() error {
+	return nil
 }
 
 type tcpSubscriber struct {
@@ -78,15 +118,19 @@ func newSubscriber(topic string, sub interface{}, opts ...server.SubscriberOptio
 			h.ctxType = typ.In(0)
 			h.reqType = typ.In(1)
 		}
+		h.stream = h.reqType != nil && isStreamType(h.reqType)
 
 		handlers = append(handlers, h)
 		ep := &registry.Endpoint{
 			Name:     "Func",
 			Request:  registry.ExtractSubValue(typ),
-			Metadata: metadata.New(2),
+			Metadata: metadata.New(3),
 		}
 		ep.Metadata.Set("topic", topic)
 		ep.Metadata.Set("subscriber", "true")
+		if h.stream {
+			ep.Metadata.Set("stream", "true")
+		}
 		endpoints = append(endpoints, ep)
 	} else {
 		hdlr := reflect.ValueOf(sub)
@@ -105,15 +149,19 @@ func newSubscriber(topic string, sub interface{}, opts ...server.SubscriberOptio
 				h.ctxType = method.Type.In(1)
 				h.reqType = method.Type.In(2)
 			}
+			h.stream = h.reqType != nil && isStreamType(h.reqType)
 
 			handlers = append(handlers, h)
 			ep := &registry.Endpoint{
 				Name:     name + "." + method.Name,
 				Request:  registry.ExtractSubValue(method.Type),
-				Metadata: metadata.New(2),
+				Metadata: metadata.New(3),
 			}
 			ep.Metadata.Set("topic", topic)
 			ep.Metadata.Set("subscriber", "true")
+			if h.stream {
+				ep.Metadata.Set("stream", "true")
+			}
 			endpoints = append(endpoints, ep)
 		}
 	}
@@ -141,7 +189,7 @@ func validateSubscriber(sub server.Subscriber) error {
 		default:
 			return fmt.Errorf("subscriber %v takes wrong number of args: %v required signature %s", name, typ.NumIn(), subSig)
 		}
-		if !isExportedOrBuiltinType(argType) {
+		if !isStreamType(argType) && !isExportedOrBuiltinType(argType) {
 			return fmt.Errorf("subscriber %v argument type not exported: %v", name, argType)
 		}
 		if typ.NumOut() != 1 {
@@ -166,7 +214,7 @@ func validateSubscriber(sub server.Subscriber) error {
 					name, method.Name, method.Type.NumIn(), subSig)
 			}
 
-			if !isExportedOrBuiltinType(argType) {
+			if !isStreamType(argType) && !isExportedOrBuiltinType(argType) {
 				return fmt.Errorf("%v argument type not exported: %v", name, argType)
 			}
 			if method.Type.NumOut() != 1 {
@@ -187,10 +235,6 @@ func (s *tcpServer) createSubHandler(sb *tcpSubscriber, opts server.Options) bro
 	return func(p broker.Event) error {
 		msg := p.Message()
 		ct := msg.Header["Content-Type"]
-		cf, err := s.newCodec(ct)
-		if err != nil {
-			return err
-		}
 
 		hdr := make(map[string]string)
 		for k, v := range msg.Header {
@@ -199,32 +243,55 @@ func (s *tcpServer) createSubHandler(sb *tcpSubscriber, opts server.Options) bro
 		delete(hdr, "Content-Type")
 		ctx := metadata.NewContext(context.Background(), hdr)
 
+		seh := subErrorHandler(opts.Context)
+
+		cf, err := s.newCodec(ct)
+		if err != nil {
+			if seh != nil {
+				return seh(ctx, sb, p, err)
+			}
+			return err
+		}
+
 		results := make(chan error, len(sb.handlers))
 
 		for i := 0; i < len(sb.handlers); i++ {
 			handler := sb.handlers[i]
 
-			var isVal bool
-			var req reflect.Value
-
-			if handler.reqType.Kind() == reflect.Ptr {
-				req = reflect.New(handler.reqType.Elem())
-			} else {
-				req = reflect.New(handler.reqType)
-				isVal = true
-			}
-			if isVal {
-				req = req.Elem()
-			}
-
 			buf := bytes.NewBuffer(msg.Body)
 
 			if err := cf.ReadHeader(buf, &codec.Message{}, codec.Event); err != nil {
+				if seh != nil {
+					return seh(ctx, sb, p, err)
+				}
 				return err
 			}
 
-			if err := cf.ReadBody(buf, req.Interface()); err != nil {
-				return err
+			var payload interface{}
+
+			if handler.stream {
+				payload = &eventStream{ctx: ctx, cf: cf, buf: buf}
+			} else {
+				var isVal bool
+				var req reflect.Value
+
+				if handler.reqType.Kind() == reflect.Ptr {
+					req = reflect.New(handler.reqType.Elem())
+				} else {
+					req = reflect.New(handler.reqType)
+					isVal = true
+				}
+				if isVal {
+					req = req.Elem()
+				}
+
+				if err := cf.ReadBody(buf, req.Interface()); err != nil {
+					if seh != nil {
+						return seh(ctx, sb, p, err)
+					}
+					return err
+				}
+				payload = req.Interface()
 			}
 
 			fn := func(ctx context.Context, msg server.Message) error {
@@ -250,10 +317,23 @@ func (s *tcpServer) createSubHandler(sb *tcpSubscriber, opts server.Options) bro
 			}
 
 			go func() {
+				if recoverPanic(opts.Context) {
+					defer func() {
+						if r := recover(); r != nil {
+							if opts.Logger.V(logger.ErrorLevel) {
+								opts.Logger.Errorf(ctx, "panic recovered in subscriber %s: %v\n%s", sb.topic, r, debug.Stack())
+							}
+							if opts.Meter != nil {
+								opts.Meter.Counter("server_panic_total", "topic", sb.topic).Inc()
+							}
+							results <- fmt.Errorf("panic recovered: %v", r)
+						}
+					}()
+				}
 				results <- fn(ctx, &tcpMessage{
 					topic:       sb.topic,
 					contentType: ct,
-					payload:     req.Interface(),
+					payload:     payload,
 					header:      msg.Header,
 					body:        msg.Body,
 					codec:       cf,
@@ -270,7 +350,11 @@ func (s *tcpServer) createSubHandler(sb *tcpSubscriber, opts server.Options) bro
 		}
 
 		if len(errors) > 0 {
-			return fmt.Errorf("subscriber error: %s", strings.Join(errors, "\n"))
+			err := fmt.Errorf("subscriber error: %s", strings.Join(errors, "\n"))
+			if seh != nil {
+				return seh(ctx, sb, p, err)
+			}
+			return err
 		}
 
 		return nil
@@ -5,7 +5,9 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net"
+	"runtime/debug"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -24,6 +26,8 @@ type tcpServer struct {
 	exit         chan chan error
 	registerOnce sync.Once
 	subscribers  map[*tcpSubscriber][]broker.Subscriber
+	// set once the dependent subsystems have been initialized
+	init bool
 	// used for first registration
 	registered bool
 	// register service instance
@@ -31,9 +35,19 @@ type tcpServer struct {
 }
 
 func (h *tcpServer) newCodec(ct string) (codec.Codec, error) {
+	if idx := strings.IndexByte(ct, ';'); idx >= 0 {
+		ct = ct[:idx]
+	}
+	ct = strings.TrimSpace(ct)
+
 	if cf, ok := h.opts.Codecs[ct]; ok {
 		return cf, nil
 	}
+
+	if cf, ok := server.DefaultCodecs[defaultContentType(h.opts.Context)]; ok {
+		return cf, nil
+	}
+
 	return nil, codec.ErrUnknownContentType
 }
 
@@ -45,10 +59,34 @@ func (h *tcpServer) Options() server.Options {
 
 func (h *tcpServer) Init(opts ...server.Option) error {
 	h.Lock()
+	defer h.Unlock()
+
 	for _, o := range opts {
 		o(&h.opts)
 	}
-	h.Unlock()
+
+	if h.init {
+		return nil
+	}
+
+	if err := h.opts.Broker.Init(); err != nil {
+		return err
+	}
+	if err := h.opts.Logger.Init(); err != nil {
+		return err
+	}
+	if err := h.opts.Tracer.Init(); err != nil {
+		return err
+	}
+	if err := h.opts.Meter.Init(); err != nil {
+		return err
+	}
+	if err := h.opts.Register.Init(); err != nil {
+		return err
+	}
+
+	h.init = true
+
 	return nil
 }
 
@@ -379,11 +417,21 @@ func (h *tcpServer) Start() error {
 			}
 		}
 
-		ch <- ts.Close()
+		// stop accepting new connections
+		err := ts.Close()
 
-		// deregister
+		// deregister before draining in-flight connections
 		h.Deregister()
 
+		timeout := gracefulTimeout(config.Context)
+		waitGraceful(waitGroup(config.Context), timeout, func() {
+			if config.Logger.V(logger.ErrorLevel) {
+				config.Logger.Errorf(config.Context, "tcp: graceful timeout %v exceeded, not waiting for in-flight connections", timeout)
+			}
+		})
+
+		ch <- err
+
 		config.Broker.Disconnect(config.Context)
 	}()
 
@@ -409,6 +457,7 @@ func (s *tcpServer) serve(ln net.Listener, h Handler) {
 	s.RLock()
 	config := s.opts
 	s.RUnlock()
+	wg := waitGroup(config.Context)
 	for {
 		c, err := ln.Accept()
 		if err != nil {
@@ -442,7 +491,31 @@ func (s *tcpServer) serve(ln net.Listener, h Handler) {
 			config.Logger.Error(config.Context, "tcp: accept err: %v", err)
 			return
 		}
-		go h.Serve(c)
+
+		if wg != nil {
+			wg.Add(1)
+		}
+		go func() {
+			if wg != nil {
+				defer wg.Done()
+			}
+			if recoverPanic(config.Context) {
+				defer func() {
+					if r := recover(); r != nil {
+						if config.Logger.V(logger.ErrorLevel) {
+							config.Logger.Errorf(config.Context, "panic recovered: %v\n%s", r, debug.Stack())
+						}
+						if config.Meter != nil {
+							config.Meter.Counter("server_panic_total").Inc()
+						}
+						if eh := errorHandler(config.Context); eh != nil {
+							eh(config.Context, s.hd, c, fmt.Errorf("panic recovered: %v", r))
+						}
+					}
+				}()
+			}
+			h.Serve(c)
+		}()
 	}
 }
 
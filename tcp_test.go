@@ -0,0 +1,34 @@
+package tcp
+
+import (
+	"testing"
+
+	"github.com/unistack-org/micro/v3/broker"
+	"github.com/unistack-org/micro/v3/server"
+)
+
+type mockBroker struct {
+	broker.Broker
+	initCount int
+}
+
+func (m *mockBroker) Init(...broker.Option) error {
+	m.initCount++
+	return nil
+}
+
+func TestInitCallsBrokerInitOnce(t *testing.T) {
+	b := &mockBroker{}
+	s := NewServer(server.Broker(b))
+
+	if err := s.Init(); err != nil {
+		t.Fatalf("Init() error: %v", err)
+	}
+	if err := s.Init(); err != nil {
+		t.Fatalf("second Init() error: %v", err)
+	}
+
+	if b.initCount != 1 {
+		t.Fatalf("expected broker Init to be called exactly once, got %d", b.initCount)
+	}
+}